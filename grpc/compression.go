@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Compression algorithms supported by Config.Compression.
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+var (
+	compressionBytesIn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_client_grpc_compression_bytes_in_total",
+		Help: "Uncompressed bytes written to the gRPC compressor, by algorithm.",
+	}, []string{"compressor"})
+
+	compressionBytesOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_client_grpc_compression_bytes_out_total",
+		Help: "Bytes written on the wire after compression, by algorithm.",
+	}, []string{"compressor"})
+
+	compressionErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_client_grpc_compression_errors_total",
+		Help: "Number of compression/decompression failures, by algorithm.",
+	}, []string{"compressor"})
+)
+
+// compressorRegisterOnce guards each algorithm's encoding.RegisterCompressor
+// call with a sync.Once, since RegisterCompressor is documented by grpc-go
+// as safe only during init() and is not safe to call concurrently with
+// itself: two Configs built on different goroutines (e.g. by concurrent
+// calls to BuildDialOptions) would otherwise race on grpc's internal
+// registry map. The metering wrapper doesn't vary by Config (the metrics it
+// records are labelled by algorithm name, not by Config), so registering
+// each algorithm once, the first time any Config asks for it, is sufficient
+// and equivalent to registering it from every Config that wants it.
+var compressorRegisterOnce = map[string]*sync.Once{
+	CompressionGzip:   new(sync.Once),
+	CompressionSnappy: new(sync.Once),
+	CompressionZstd:   new(sync.Once),
+}
+
+// registerCompressors installs the gRPC encoding.Compressor for
+// c.Compression as the process-wide compressor for that name, wrapped only
+// to record the bytes in/out/error metrics above. It is a no-op for
+// CompressionNone/"".
+//
+// The wire format produced is exactly what the underlying gzip/snappy/zstd
+// implementation produces: nothing is prepended or otherwise altered, so the
+// bytes sent under "grpc-encoding: gzip" (etc.) remain decodable by any
+// standards-conformant gRPC peer, including the real Loki push server.
+//
+// encoding.RegisterCompressor has no per-connection scoping: it replaces the
+// global "gzip"/"snappy"/"zstd" codec for every gRPC client and server in
+// the process, including ones unrelated to this Config. registerCompressors
+// only ever calls it once per algorithm, via compressorRegisterOnce, so
+// concurrent calls to BuildDialOptions across multiple Configs can't race on
+// grpc's internal registry.
+func (c *Config) registerCompressors() error {
+	var inner encoding.Compressor
+
+	switch c.Compression {
+	case "", CompressionNone:
+		return nil
+	case CompressionGzip:
+		inner = gzipCompressor{}
+	case CompressionSnappy:
+		inner = snappyCompressor{}
+	case CompressionZstd:
+		inner = zstdCompressor{}
+	default:
+		return fmt.Errorf("unknown compression %q, must be one of %q, %q, %q, %q", c.Compression, CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd)
+	}
+
+	compressorRegisterOnce[c.Compression].Do(func() {
+		encoding.RegisterCompressor(meteringCompressor{inner: inner})
+	})
+
+	return nil
+}
+
+// compressionDialOption returns a dial option that applies
+// grpc.UseCompressor(c.Compression) as a default to every call, or nil if
+// compression is disabled or MinCompressSize is set
+// (in which case the decision is left to CompressionCallOption, made per
+// call once the message size is known).
+func (c *Config) compressionDialOption() grpc.DialOption {
+	if c.Compression == "" || c.Compression == CompressionNone {
+		return nil
+	}
+	if c.MinCompressSize > 0 {
+		return nil
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(c.Compression))
+}
+
+// CompressionCallOption returns the grpc.CallOption that should be passed to
+// a Push call sending a payloadSize-byte message, given c.Compression and
+// c.MinCompressSize. It returns nil (no call option, i.e. the call goes out
+// uncompressed) for payloads smaller than MinCompressSize, to avoid paying
+// compression framing overhead on tiny batches; BuildDialOptions already
+// applies grpc.UseCompressor as a default for every call when MinCompressSize
+// is 0, so callers only need this when they set MinCompressSize.
+func (c *Config) CompressionCallOption(payloadSize int) grpc.CallOption {
+	if c.Compression == "" || c.Compression == CompressionNone {
+		return nil
+	}
+	if payloadSize < c.MinCompressSize {
+		return nil
+	}
+	return grpc.UseCompressor(c.Compression)
+}
+
+// gzipCompressor adapts compress/gzip to encoding.Compressor.
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return CompressionGzip }
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// snappyCompressor adapts the already-vendored github.com/golang/snappy to
+// encoding.Compressor.
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string { return CompressionSnappy }
+
+func (snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
+
+// zstdCompressor adapts github.com/klauspost/compress/zstd to
+// encoding.Compressor.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string { return CompressionZstd }
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	// encoding.Compressor.Decompress returns a plain io.Reader, so grpc never
+	// calls dec.Close(); release its background resources ourselves once the
+	// message has been fully read.
+	return closeOnEOFReader{Reader: dec, closer: dec}, nil
+}
+
+type closeOnEOFReader struct {
+	io.Reader
+	closer interface{ Close() }
+}
+
+func (c closeOnEOFReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if err != nil {
+		c.closer.Close()
+	}
+	return n, err
+}
+
+// meteringCompressor wraps another encoding.Compressor to record the
+// bytes-in/bytes-out/error metrics above, without altering the bytes it
+// produces or consumes in any way. It is purely an observability layer: a
+// peer that doesn't register it still interops fine with the wrapped
+// algorithm's standard wire format.
+type meteringCompressor struct {
+	inner encoding.Compressor
+}
+
+func (m meteringCompressor) Name() string { return m.inner.Name() }
+
+func (m meteringCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	counted := &byteCountWriter{w: w}
+	wc, err := m.inner.Compress(counted)
+	if err != nil {
+		compressionErrors.WithLabelValues(m.Name()).Inc()
+		return nil, err
+	}
+	return &meteringWriteCloser{WriteCloser: wc, name: m.Name(), out: counted}, nil
+}
+
+func (m meteringCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	reader, err := m.inner.Decompress(r)
+	if err != nil {
+		compressionErrors.WithLabelValues(m.Name()).Inc()
+	}
+	return reader, err
+}
+
+// meteringWriteCloser tracks the uncompressed bytes written to it and the
+// compressed bytes that reach the wire via out, reporting both as metrics
+// once the message is fully written.
+type meteringWriteCloser struct {
+	io.WriteCloser
+	name string
+	in   int
+	out  *byteCountWriter
+}
+
+func (m *meteringWriteCloser) Write(p []byte) (int, error) {
+	n, err := m.WriteCloser.Write(p)
+	m.in += n
+	return n, err
+}
+
+func (m *meteringWriteCloser) Close() error {
+	err := m.WriteCloser.Close()
+	if err != nil {
+		compressionErrors.WithLabelValues(m.name).Inc()
+	}
+	compressionBytesIn.WithLabelValues(m.name).Add(float64(m.in))
+	compressionBytesOut.WithLabelValues(m.name).Add(float64(m.out.n))
+	return err
+}
+
+// byteCountWriter tracks how many bytes flow through to the wire, for the
+// compression ratio metrics.
+type byteCountWriter struct {
+	w io.Writer
+	n int
+}
+
+func (b *byteCountWriter) Write(p []byte) (int, error) {
+	n, err := b.w.Write(p)
+	b.n += n
+	return n, err
+}
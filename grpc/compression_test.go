@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompressorRoundTrip exercises each registered algorithm's Compress ->
+// Decompress path directly, independent of encoding.RegisterCompressor's
+// process-global state, and asserts the payload comes back unchanged.
+func TestCompressorRoundTrip(t *testing.T) {
+	algos := []struct {
+		name string
+		c    interface {
+			Name() string
+			Compress(io.Writer) (io.WriteCloser, error)
+			Decompress(io.Reader) (io.Reader, error)
+		}
+	}{
+		{CompressionGzip, gzipCompressor{}},
+		{CompressionSnappy, snappyCompressor{}},
+		{CompressionZstd, zstdCompressor{}},
+	}
+
+	for _, a := range algos {
+		t.Run(a.name, func(t *testing.T) {
+			payload := []byte("the quick brown fox jumps over the lazy dog, repeated for compressibility, " +
+				"the quick brown fox jumps over the lazy dog")
+
+			var wire bytes.Buffer
+			wc, err := a.c.Compress(&wire)
+			require.NoError(t, err)
+			_, err = wc.Write(payload)
+			require.NoError(t, err)
+			require.NoError(t, wc.Close())
+
+			r, err := a.c.Decompress(&wire)
+			require.NoError(t, err)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+
+			assert.Equal(t, payload, got)
+		})
+	}
+}
+
+// TestMeteringCompressorPreservesWireFormat ensures the metrics wrapper
+// installed by registerCompressors doesn't change a single byte on the wire:
+// a plain gzipCompressor must be able to decompress what meteringCompressor
+// produced, since a real gRPC peer only ever sees the plain algorithm.
+func TestMeteringCompressorPreservesWireFormat(t *testing.T) {
+	payload := []byte("hello from a real gRPC peer that knows nothing about this client's metrics wrapper")
+
+	metering := meteringCompressor{inner: gzipCompressor{}}
+
+	var wire bytes.Buffer
+	wc, err := metering.Compress(&wire)
+	require.NoError(t, err)
+	_, err = wc.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, wc.Close())
+
+	// A peer with no knowledge of meteringCompressor, using the plain
+	// standard-name codec, must decompress this without error.
+	r, err := (gzipCompressor{}).Decompress(&wire)
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+}
+
+func TestCompressionCallOption(t *testing.T) {
+	cfg := Config{Compression: CompressionGzip, MinCompressSize: 100}
+
+	assert.Nil(t, cfg.CompressionCallOption(10))
+	assert.NotNil(t, cfg.CompressionCallOption(1000))
+
+	noneCfg := Config{Compression: CompressionNone}
+	assert.Nil(t, noneCfg.CompressionCallOption(1000))
+}
+
+func TestRegisterCompressorsUnknownAlgorithm(t *testing.T) {
+	cfg := Config{Compression: "lz4"}
+	err := cfg.registerCompressors()
+	assert.Error(t, err)
+}
+
+// TestRegisterCompressorsConcurrentSafe exercises registerCompressors from
+// many goroutines at once, as concurrent calls to BuildDialOptions from
+// multiple Configs would. encoding.RegisterCompressor is documented as not
+// safe for concurrent use, so this only stays race-free because
+// registerCompressors guards each algorithm with a sync.Once; run with
+// `go test -race` to verify.
+func TestRegisterCompressorsConcurrentSafe(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg := Config{Compression: CompressionGzip}
+			assert.NoError(t, cfg.registerCompressors())
+		}()
+	}
+	wg.Wait()
+}
@@ -98,6 +98,21 @@ func TestBuildDialOptionsWithTLS(t *testing.T) {
 	assert.NotEmpty(t, opts)
 }
 
+func TestBuildDialOptionsRejectsInvalidTLS(t *testing.T) {
+	cfg := Config{
+		KeepAlive:        30 * time.Second,
+		KeepAliveTimeout: 5 * time.Second,
+		TLS: TLSConfig{
+			Enabled: true,
+			Profile: TLSProfileMTLS,
+		},
+	}
+
+	_, err := cfg.BuildDialOptions()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), TLSProfileMTLS)
+}
+
 func TestConfigUnmarshalYAML(t *testing.T) {
 	var cfg Config
 	err := cfg.UnmarshalYAML(func(v interface{}) error {
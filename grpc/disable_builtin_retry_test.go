@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/netobserv/loki-client-go/pkg/backoff"
+)
+
+func TestSkipBuiltinBackoff(t *testing.T) {
+	cfg := Config{
+		ServiceConfig:       ServiceConfig{RetryPolicy: &RetryPolicy{MaxAttempts: 5}},
+		DisableBuiltinRetry: true,
+	}
+	assert.True(t, cfg.SkipBuiltinBackoff())
+
+	cfg.DisableBuiltinRetry = false
+	assert.False(t, cfg.SkipBuiltinBackoff())
+
+	var noPolicy Config
+	noPolicy.DisableBuiltinRetry = true
+	assert.False(t, noPolicy.SkipBuiltinBackoff())
+}
+
+func TestRetryMaxAttemptsFlagZeroDisablesPolicy(t *testing.T) {
+	var cfg Config
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(f)
+
+	require.NoError(t, f.Parse([]string{"-grpc.service-config.retry-max-attempts=5"}))
+	require.NotNil(t, cfg.ServiceConfig.RetryPolicy)
+	assert.Equal(t, 5, cfg.ServiceConfig.RetryPolicy.MaxAttempts)
+
+	require.NoError(t, f.Parse([]string{"-grpc.service-config.retry-max-attempts=0"}))
+	assert.Nil(t, cfg.ServiceConfig.RetryPolicy)
+}
+
+func TestRetryInterceptorNilCases(t *testing.T) {
+	var disabled Config
+	assert.Nil(t, disabled.retryInterceptor(), "DisableBuiltinRetry not set")
+
+	noMaxRetries := Config{DisableBuiltinRetry: true}
+	assert.Nil(t, noMaxRetries.retryInterceptor(), "BackoffConfig.MaxRetries is 0")
+
+	skipped := Config{
+		DisableBuiltinRetry: true,
+		BackoffConfig:       backoff.BackoffConfig{MaxRetries: 3},
+		ServiceConfig:       ServiceConfig{RetryPolicy: &RetryPolicy{MaxAttempts: 5}},
+	}
+	assert.Nil(t, skipped.retryInterceptor(), "a ServiceConfig policy already covers retries")
+}
+
+func TestRetryInterceptorRetriesRetryableCodes(t *testing.T) {
+	cfg := Config{
+		DisableBuiltinRetry: true,
+		BackoffConfig: backoff.BackoffConfig{
+			MaxRetries: 2,
+			MinBackoff: time.Millisecond,
+			MaxBackoff: 2 * time.Millisecond,
+		},
+	}
+	interceptor := cfg.retryInterceptor()
+	require.NotNil(t, interceptor)
+
+	var attempts int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts++
+		if attempts < 3 {
+			return status.Error(codes.Unavailable, "not ready yet")
+		}
+		return nil
+	}
+
+	err := interceptor(context.Background(), "/Push", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryInterceptorDoesNotRetryNonRetryableCodes(t *testing.T) {
+	cfg := Config{
+		DisableBuiltinRetry: true,
+		BackoffConfig:       backoff.BackoffConfig{MaxRetries: 5, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+	interceptor := cfg.retryInterceptor()
+	require.NotNil(t, interceptor)
+
+	var attempts int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts++
+		return status.Error(codes.InvalidArgument, "bad request")
+	}
+
+	err := interceptor(context.Background(), "/Push", nil, nil, nil, invoker)
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// newProxyDialer builds a grpc.WithContextDialer-compatible dialer that
+// routes the connection to ServerAddress through the configured HTTP CONNECT
+// or SOCKS5 proxy, or returns nil if no proxy is configured for this dial.
+func (c *Config) newProxyDialer() (func(ctx context.Context, addr string) (net.Conn, error), error) {
+	proxyURL := c.ProxyURL
+	if proxyURL == "" && c.ProxyFromEnvironment {
+		if resolved := httpProxyFromEnvironment(c.ServerAddress); resolved != "" {
+			proxyURL = resolved
+		}
+	}
+	if proxyURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL %q: %w", proxyURL, err)
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if proxyBypassed(addr, c.NoProxy) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+
+		switch u.Scheme {
+		case "socks5", "socks5h":
+			return dialSOCKS5(ctx, u, network, addr)
+		case "http", "https":
+			return dialHTTPConnect(ctx, u, addr)
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		return dial(ctx, "tcp", addr)
+	}, nil
+}
+
+// dialSOCKS5 dials addr through the SOCKS5 proxy described by proxyURL,
+// forwarding any userinfo as proxy authentication.
+func dialSOCKS5(ctx context.Context, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		auth = &proxy.Auth{User: proxyURL.User.Username()}
+		if pass, ok := proxyURL.User.Password(); ok {
+			auth.Password = pass
+		}
+	}
+
+	dialer, err := proxy.SOCKS5(network, proxyURL.Host, auth, &net.Dialer{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SOCKS5 dialer: %w", err)
+	}
+
+	if ctxDialer, ok := dialer.(proxy.ContextDialer); ok {
+		return ctxDialer.DialContext(ctx, network, addr)
+	}
+	return dialer.Dial(network, addr)
+}
+
+// dialHTTPConnect dials the proxy and performs an HTTP CONNECT handshake to
+// addr, returning the tunnelled connection for gRPC to layer TLS over. If
+// proxyURL's scheme is "https", the connection to the proxy itself is
+// wrapped in TLS before the CONNECT request is written, matching what
+// net/http's own transport does for HTTPS proxies: otherwise the
+// Proxy-Authorization header and the CONNECT request would go out in
+// cleartext to a proxy that expects a TLS-protected connection.
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial proxy %s: %w", proxyURL.Host, err)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed TLS handshake with proxy %s: %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		if pass, ok := proxyURL.User.Password(); ok {
+			creds := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + pass))
+			req.Header.Set("Proxy-Authorization", "Basic "+creds)
+		}
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write CONNECT request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", addr, resp.Status)
+	}
+
+	// bufio.Reader may have buffered bytes past the CONNECT response headers
+	// (e.g. the start of the TLS ServerHello, if the proxy pipelined it).
+	// Those bytes belong to the tunnelled connection, not the HTTP response,
+	// so they must be replayed to whatever reads from conn next rather than
+	// discarded with br.
+	if n := br.Buffered(); n > 0 {
+		pending := make([]byte, n)
+		if _, err := io.ReadFull(br, pending); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to drain buffered CONNECT response bytes: %w", err)
+		}
+		return &bufferedConn{Conn: conn, pending: pending}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn serves pending bytes already read off the wire (but not yet
+// consumed by the caller) before falling back to the underlying net.Conn,
+// mirroring how net/http's own transport handles leftover bytes after a
+// CONNECT tunnel is established.
+type bufferedConn struct {
+	net.Conn
+	pending []byte
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	if len(b.pending) > 0 {
+		n := copy(p, b.pending)
+		b.pending = b.pending[n:]
+		return n, nil
+	}
+	return b.Conn.Read(p)
+}
+
+// proxyBypassed reports whether addr's host matches one of the no-proxy
+// patterns, using the same suffix-matching semantics as the NO_PROXY
+// environment variable.
+func proxyBypassed(addr string, noProxy []string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	for _, pattern := range noProxy {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if pattern == "*" || host == pattern || strings.HasSuffix(host, "."+strings.TrimPrefix(pattern, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpProxyFromEnvironment resolves the proxy that the standard HTTP_PROXY/
+// HTTPS_PROXY/NO_PROXY environment variables would select for addr.
+func httpProxyFromEnvironment(addr string) string {
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: addr}}
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	if err != nil || proxyURL == nil {
+		return ""
+	}
+	return proxyURL.String()
+}
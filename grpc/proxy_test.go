@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startCONNECTProxy starts a minimal HTTP CONNECT proxy that, for every
+// accepted connection, writes a 200 response followed immediately by extra
+// bytes on the same write (simulating a proxy that pipelines the start of
+// the tunnelled data right after the CONNECT response).
+func startCONNECTProxy(t *testing.T, extra []byte) *url.URL {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		br := bufio.NewReader(conn)
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		conn.Write(append([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"), extra...))
+
+		// Keep the connection open briefly so the client can read extra
+		// without the proxy tearing the socket down first.
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	return &url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
+
+func TestDialHTTPConnectReplaysBufferedBytes(t *testing.T) {
+	extra := []byte("pipelined-tls-hello")
+	proxyURL := startCONNECTProxy(t, extra)
+
+	conn, err := dialHTTPConnect(context.Background(), proxyURL, "backend.example.com:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	got := make([]byte, len(extra))
+	_, err = io.ReadFull(conn, got)
+	require.NoError(t, err)
+	require.Equal(t, extra, got)
+}
+
+func TestDialHTTPConnectWrapsHTTPSProxyInTLS(t *testing.T) {
+	// startCONNECTProxy speaks plaintext HTTP CONNECT, not TLS. Pointing an
+	// https:// proxy URL at it must make dialHTTPConnect attempt a TLS
+	// handshake before ever writing the CONNECT request, which that
+	// handshake will fail against a plaintext listener -- proving the
+	// https scheme isn't silently treated the same as http.
+	proxyURL := startCONNECTProxy(t, nil)
+	proxyURL.Scheme = "https"
+
+	_, err := dialHTTPConnect(context.Background(), proxyURL, "backend.example.com:443")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "TLS handshake")
+}
+
+func TestProxyBypassed(t *testing.T) {
+	noProxy := []string{"internal.example.com", ".corp.example.com", "  "}
+
+	require.True(t, proxyBypassed("internal.example.com:443", noProxy))
+	require.True(t, proxyBypassed("svc.corp.example.com:443", noProxy))
+	require.False(t, proxyBypassed("loki.example.com:443", noProxy))
+}
@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceConfig describes gRPC-native retry/hedging behaviour, translated by
+// buildServiceConfigJSON into the JSON service config gRPC expects. At most
+// one of RetryPolicy and HedgingPolicy should be set, since gRPC does not
+// allow both on the same method config; if both are set, RetryPolicy wins.
+type ServiceConfig struct {
+	RetryPolicy   *RetryPolicy   `yaml:"retry_policy"`
+	HedgingPolicy *HedgingPolicy `yaml:"hedging_policy"`
+}
+
+// RetryPolicy mirrors gRPC's retryPolicy service config stanza.
+type RetryPolicy struct {
+	MaxAttempts          int           `yaml:"max_attempts"`
+	InitialBackoff       time.Duration `yaml:"initial_backoff"`
+	MaxBackoff           time.Duration `yaml:"max_backoff"`
+	BackoffMultiplier    float64       `yaml:"backoff_multiplier"`
+	RetryableStatusCodes []string      `yaml:"retryable_status_codes"`
+}
+
+// HedgingPolicy mirrors gRPC's hedgingPolicy service config stanza.
+type HedgingPolicy struct {
+	MaxAttempts         int           `yaml:"max_attempts"`
+	HedgingDelay        time.Duration `yaml:"hedging_delay"`
+	NonFatalStatusCodes []string      `yaml:"non_fatal_status_codes"`
+}
+
+// DefaultRetryableStatusCodes mirrors the HTTP-mapped codes already
+// recognised by getStatusCode (503/429/504).
+var DefaultRetryableStatusCodes = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED", "DEADLINE_EXCEEDED"}
+
+// DefaultBackoffMultiplier is the exponential backoff multiplier used when a
+// RetryPolicy is configured without one, and by retryInterceptor.
+const DefaultBackoffMultiplier = 2.0
+
+// grpcMethodConfig/grpcRetryPolicy/grpcHedgingPolicy mirror the JSON schema
+// gRPC expects for a service config (see grpc.WithDefaultServiceConfig).
+type grpcServiceConfig struct {
+	MethodConfig []grpcMethodConfig `json:"methodConfig"`
+}
+
+type grpcMethodConfig struct {
+	Name          []grpcMethodName   `json:"name"`
+	RetryPolicy   *grpcRetryPolicy   `json:"retryPolicy,omitempty"`
+	HedgingPolicy *grpcHedgingPolicy `json:"hedgingPolicy,omitempty"`
+}
+
+// grpcMethodName left entirely empty matches every method on every service.
+type grpcMethodName struct{}
+
+type grpcRetryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type grpcHedgingPolicy struct {
+	MaxAttempts         int      `json:"maxAttempts"`
+	HedgingDelay        string   `json:"hedgingDelay"`
+	NonFatalStatusCodes []string `json:"nonFatalStatusCodes"`
+}
+
+// buildServiceConfigJSON translates c.ServiceConfig into the JSON document
+// expected by grpc.WithDefaultServiceConfig, or returns "" if neither a
+// retry nor a hedging policy is configured.
+func (c *Config) buildServiceConfigJSON() (string, error) {
+	method := grpcMethodConfig{Name: []grpcMethodName{{}}}
+
+	switch {
+	case c.ServiceConfig.RetryPolicy != nil:
+		rp := c.ServiceConfig.RetryPolicy
+		codes := rp.RetryableStatusCodes
+		if len(codes) == 0 {
+			codes = DefaultRetryableStatusCodes
+		}
+		multiplier := rp.BackoffMultiplier
+		if multiplier == 0 {
+			multiplier = DefaultBackoffMultiplier
+		}
+		maxAttempts := rp.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = DefaultMaxRetries
+		}
+		initialBackoff := rp.InitialBackoff
+		if initialBackoff == 0 {
+			initialBackoff = DefaultMinBackoff
+		}
+		maxBackoff := rp.MaxBackoff
+		if maxBackoff == 0 {
+			maxBackoff = DefaultMaxBackoff
+		}
+		method.RetryPolicy = &grpcRetryPolicy{
+			MaxAttempts:          maxAttempts,
+			InitialBackoff:       formatGRPCDuration(initialBackoff),
+			MaxBackoff:           formatGRPCDuration(maxBackoff),
+			BackoffMultiplier:    multiplier,
+			RetryableStatusCodes: codes,
+		}
+	case c.ServiceConfig.HedgingPolicy != nil:
+		hp := c.ServiceConfig.HedgingPolicy
+		method.HedgingPolicy = &grpcHedgingPolicy{
+			MaxAttempts:         hp.MaxAttempts,
+			HedgingDelay:        formatGRPCDuration(hp.HedgingDelay),
+			NonFatalStatusCodes: hp.NonFatalStatusCodes,
+		}
+	default:
+		return "", nil
+	}
+
+	doc, err := json.Marshal(grpcServiceConfig{MethodConfig: []grpcMethodConfig{method}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal gRPC service config: %w", err)
+	}
+	return string(doc), nil
+}
+
+// formatGRPCDuration renders d in the fractional-seconds-plus-"s" format
+// gRPC service config durations use, e.g. 500ms -> "0.5s".
+func formatGRPCDuration(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// SkipBuiltinBackoff reports whether the batch-level send loop should skip
+// its own BackoffConfig-driven backoff and retry count before handing
+// control back to gRPC. It is true once both a gRPC-native retry or hedging
+// policy is configured and the caller has opted into it via
+// DisableBuiltinRetry, so the two retry mechanisms don't stack.
+func (c *Config) SkipBuiltinBackoff() bool {
+	if !c.DisableBuiltinRetry {
+		return false
+	}
+	return c.ServiceConfig.RetryPolicy != nil || c.ServiceConfig.HedgingPolicy != nil
+}
+
+// defaultRetryableCodes mirrors DefaultRetryableStatusCodes as codes.Code
+// values, for the client-side retryInterceptor below.
+var defaultRetryableCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded}
+
+// isRetryableCode reports whether code appears in defaultRetryableCodes.
+func isRetryableCode(code codes.Code) bool {
+	for _, c := range defaultRetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryInterceptor returns a grpc.UnaryClientInterceptor implementing
+// BackoffConfig-driven retries for unary calls, or nil if DisableBuiltinRetry
+// is not set or BackoffConfig.MaxRetries is 0. It exists so that
+// DisableBuiltinRetry actually disables something: when a gRPC-native
+// RetryPolicy or HedgingPolicy is also configured (SkipBuiltinBackoff()
+// true), this interceptor is not installed at all, since gRPC's own
+// retry/hedging machinery already covers the call and stacking both would
+// retry each attempt twice.
+func (c *Config) retryInterceptor() grpc.UnaryClientInterceptor {
+	if !c.DisableBuiltinRetry || c.SkipBuiltinBackoff() {
+		return nil
+	}
+
+	maxRetries := c.BackoffConfig.MaxRetries
+	if maxRetries == 0 {
+		return nil
+	}
+	minBackoff := c.BackoffConfig.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	maxBackoff := c.BackoffConfig.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		backoff := minBackoff
+
+		var err error
+		for attempt := 0; attempt <= maxRetries; attempt++ {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || !isRetryableCode(status.Code(err)) || attempt == maxRetries {
+				return err
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+
+			backoff *= time.Duration(DefaultBackoffMultiplier)
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildServiceConfigJSONDefaultsRetryPolicy(t *testing.T) {
+	cfg := Config{
+		ServiceConfig: ServiceConfig{
+			RetryPolicy: &RetryPolicy{MaxAttempts: 5},
+		},
+	}
+
+	doc, err := cfg.buildServiceConfigJSON()
+	require.NoError(t, err)
+
+	assert.Contains(t, doc, `"maxAttempts":5`)
+	assert.Contains(t, doc, `"initialBackoff":"0.5s"`)
+	assert.Contains(t, doc, `"maxBackoff":"300s"`)
+	assert.Contains(t, doc, `"backoffMultiplier":2`)
+}
+
+func TestBuildServiceConfigJSONDefaultsAppliedWhenRetryPolicyEmpty(t *testing.T) {
+	cfg := Config{
+		ServiceConfig: ServiceConfig{
+			RetryPolicy: &RetryPolicy{},
+		},
+	}
+
+	doc, err := cfg.buildServiceConfigJSON()
+	require.NoError(t, err)
+
+	assert.Contains(t, doc, `"maxAttempts":10`)
+	assert.Contains(t, doc, `"initialBackoff":"0.5s"`)
+	assert.Contains(t, doc, `"maxBackoff":"300s"`)
+}
+
+func TestBuildServiceConfigJSONNoPolicy(t *testing.T) {
+	var cfg Config
+	doc, err := cfg.buildServiceConfigJSON()
+	require.NoError(t, err)
+	assert.Empty(t, doc)
+}
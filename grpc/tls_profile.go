@@ -0,0 +1,154 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// TLS profiles supported by TLSConfig.Profile.
+const (
+	// TLSProfileClient is the default profile: verify the server using
+	// CAFile (or the system trust store), with an optional client
+	// certificate.
+	TLSProfileClient = "client"
+
+	// TLSProfileMTLS requires both a client certificate/key and a CA file,
+	// rejecting servers that don't present a certificate chain rooted in
+	// CAFile.
+	TLSProfileMTLS = "mtls"
+
+	// TLSProfileAuto generates an in-memory, self-signed ECDSA certificate
+	// at startup. Intended for local development and tests against an
+	// untrusted server with InsecureSkipVerify.
+	TLSProfileAuto = "auto"
+)
+
+// Validate checks that the TLS configuration is internally consistent,
+// returning an error describing the first problem found.
+func (t *TLSConfig) Validate() error {
+	switch t.Profile {
+	case "", TLSProfileClient, TLSProfileAuto:
+		// no extra requirements
+	case TLSProfileMTLS:
+		if t.CertFile == "" || t.KeyFile == "" || t.CAFile == "" {
+			return fmt.Errorf("tls profile %q requires cert_file, key_file and ca_file to all be set", TLSProfileMTLS)
+		}
+	default:
+		return fmt.Errorf("unknown tls profile %q, must be one of %q, %q, %q", t.Profile, TLSProfileClient, TLSProfileMTLS, TLSProfileAuto)
+	}
+
+	if _, err := tlsVersionFromString(t.MinVersion); err != nil {
+		return fmt.Errorf("invalid min_version: %w", err)
+	}
+	if _, err := tlsVersionFromString(t.MaxVersion); err != nil {
+		return fmt.Errorf("invalid max_version: %w", err)
+	}
+
+	for _, name := range t.CipherSuites {
+		if _, ok := cipherSuiteByName(name); !ok {
+			return fmt.Errorf("unknown cipher suite %q", name)
+		}
+	}
+
+	return nil
+}
+
+// tlsVersionFromString maps a version string such as "1.2" or "1.3" to its
+// tls.VersionTLSxx constant. An empty string means "unset" and returns 0.
+func tlsVersionFromString(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q", version)
+	}
+}
+
+// cipherSuiteByName looks up a cipher suite by its standard name (e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256") among both the secure and
+// insecure suites known to crypto/tls.
+func cipherSuiteByName(name string) (*tls.CipherSuite, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite, true
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			return suite, true
+		}
+	}
+	return nil, false
+}
+
+// cipherSuiteIDs resolves a list of cipher suite names to their IDs, for use
+// as tls.Config.CipherSuites. It assumes Validate has already been called.
+func cipherSuiteIDs(names []string) []uint16 {
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		if suite, ok := cipherSuiteByName(name); ok {
+			ids = append(ids, suite.ID)
+		}
+	}
+	return ids
+}
+
+// generateAutoTLSCertificate creates an in-memory ECDSA keypair and
+// self-signed certificate for the TLSProfileAuto profile, logging its SHA-256
+// fingerprint so operators can recognise it in server-side logs.
+func generateAutoTLSCertificate(logger log.Logger) (tls.Certificate, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate auto TLS key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate auto TLS serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "loki-client-go auto TLS"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create auto TLS certificate: %w", err)
+	}
+
+	fingerprint := sha256.Sum256(der)
+	level.Info(logger).Log("msg", "generated self-signed TLS certificate for auto profile", "sha256_fingerprint", fmt.Sprintf("%x", fingerprint))
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  priv,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfigValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr bool
+	}{
+		{"default profile", TLSConfig{}, false},
+		{"client profile", TLSConfig{Profile: TLSProfileClient}, false},
+		{"auto profile", TLSConfig{Profile: TLSProfileAuto}, false},
+		{"mtls profile missing files", TLSConfig{Profile: TLSProfileMTLS}, true},
+		{"mtls profile with files", TLSConfig{
+			Profile:  TLSProfileMTLS,
+			CertFile: "cert.pem",
+			KeyFile:  "key.pem",
+			CAFile:   "ca.pem",
+		}, false},
+		{"unknown profile", TLSConfig{Profile: "bogus"}, true},
+		{"invalid min version", TLSConfig{MinVersion: "0.9"}, true},
+		{"invalid cipher suite", TLSConfig{CipherSuites: []string{"bogus"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tls.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTLSVersionFromString(t *testing.T) {
+	v, err := tlsVersionFromString("1.3")
+	require.NoError(t, err)
+	assert.NotZero(t, v)
+
+	_, err = tlsVersionFromString("bogus")
+	assert.Error(t, err)
+}
+
+func TestGenerateAutoTLSCertificate(t *testing.T) {
+	cert, err := generateAutoTLSCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cert.Certificate)
+}
@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// reloadingTLS watches the configured certificate, key and CA files on disk
+// and keeps the parsed material up to date without requiring the client to
+// be restarted, e.g. when certificates are rotated by cert-manager.
+type reloadingTLS struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	serverName string
+	logger     log.Logger
+
+	cert atomic.Value // *tls.Certificate
+	pool atomic.Value // *x509.CertPool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newReloadingTLS performs an initial load of the configured files and, if
+// interval is non-zero, starts a background goroutine that reloads them on
+// that interval. The initial load must succeed; subsequent reload failures
+// are logged and the previously loaded material is kept in place. serverName
+// is the expected peer hostname, checked by VerifyPeerCertificate exactly as
+// Go's default verification would check it via tls.Config.ServerName.
+func newReloadingTLS(certFile, keyFile, caFile, serverName string, interval time.Duration, logger log.Logger) (*reloadingTLS, error) {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	r := &reloadingTLS{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		serverName: serverName,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		r.wg.Add(1)
+		go r.watch(interval)
+	}
+
+	return r, nil
+}
+
+// reload re-reads the certificate/key and CA files from disk and, on
+// success, atomically swaps the cached values.
+func (r *reloadingTLS) reload() error {
+	if r.certFile != "" && r.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate/key: %w", err)
+		}
+		r.cert.Store(&cert)
+	}
+
+	if r.caFile != "" {
+		caCert, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate file %s: %w", r.caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("failed to parse CA certificate from %s", r.caFile)
+		}
+		r.pool.Store(pool)
+	}
+
+	return nil
+}
+
+func (r *reloadingTLS) watch(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				level.Error(r.logger).Log("msg", "failed to reload TLS material, keeping previous certificate/CA pool", "err", err)
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Close stops the background watcher, if one was started.
+func (r *reloadingTLS) Close() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+// GetClientCertificate implements the signature expected by
+// tls.Config.GetClientCertificate, serving the most recently loaded client
+// certificate.
+func (r *reloadingTLS) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := r.cert.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no client certificate configured")
+	}
+	return cert, nil
+}
+
+// VerifyPeerCertificate implements the signature expected by
+// tls.Config.VerifyPeerCertificate, verifying the presented chain against
+// the most recently loaded CA pool rather than the one captured at dial
+// time, and against r.serverName exactly as Go's default verification would
+// check tls.Config.ServerName. Callers that set this as
+// tls.Config.VerifyPeerCertificate must also set InsecureSkipVerify, since
+// it entirely replaces Go's own verification.
+func (r *reloadingTLS) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	pool, _ := r.pool.Load().(*x509.CertPool)
+	if pool == nil {
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse peer certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("no peer certificate presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       r.serverName,
+		Roots:         pool,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSignedCA generates a self-signed CA certificate valid for
+// dnsName and writes its PEM encoding to dir/ca.pem, returning the raw DER
+// bytes so the test can present the same certificate as the "peer" chain.
+func writeSelfSignedCA(t *testing.T, dir, dnsName string) []byte {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: dnsName},
+		DNSNames:              []string{dnsName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), pemBytes, 0o600))
+
+	return der
+}
+
+func TestReloadingTLSVerifyPeerCertificateChecksHostname(t *testing.T) {
+	dir := t.TempDir()
+	der := writeSelfSignedCA(t, dir, "loki.example.com")
+
+	r, err := newReloadingTLS("", "", filepath.Join(dir, "ca.pem"), "loki.example.com", 0, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, r.VerifyPeerCertificate([][]byte{der}, nil))
+
+	mismatched, err := newReloadingTLS("", "", filepath.Join(dir, "ca.pem"), "someone-else.example.com", 0, nil)
+	require.NoError(t, err)
+
+	err = mismatched.VerifyPeerCertificate([][]byte{der}, nil)
+	require.Error(t, err)
+}